@@ -0,0 +1,510 @@
+package dynami
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	exp "github.com/robskie/dynami/expression"
+	sc "github.com/robskie/dynami/schema"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dbattribute "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const clientRequestTokenLength = 32
+
+// Client wraps a *dynamodb.Client with dynami's struct-tag-driven item
+// marshalling and keying. All of its methods take a context.Context as
+// their first argument, matching the underlying aws-sdk-go-v2 client.
+type Client struct {
+	db *dynamodb.Client
+
+	resolver       sc.TagResolver
+	marshalOpts    MarshalOptions
+	insecureRandom bool
+}
+
+// Option configures a Client returned by NewClient.
+type Option func(*Client)
+
+// WithMarshalOptions sets the MarshalOptions a Client uses to strip
+// empty-looking attributes before PutItem/PutTransactItem. The zero
+// value (the default) reproduces removeEmptyAttr's original behavior.
+func WithMarshalOptions(opts MarshalOptions) Option {
+	return func(c *Client) { c.marshalOpts = opts }
+}
+
+// WithTagResolver sets the schema.TagResolver a Client uses to resolve
+// struct tags into attribute names and keys, overriding
+// schema.DefaultResolver for this Client only. Most callers never need
+// this; it exists for tests and for programs embedding more than one
+// tag convention in the same process.
+func WithTagResolver(r sc.TagResolver) Option {
+	return func(c *Client) { c.resolver = r }
+}
+
+// tagResolver returns c's TagResolver, falling back to
+// schema.DefaultResolver when none was set with WithTagResolver.
+func (c *Client) tagResolver() sc.TagResolver {
+	if c.resolver != nil {
+		return c.resolver
+	}
+	return sc.DefaultResolver()
+}
+
+// WithInsecureRandom switches this Client's default ClientRequestTokens
+// from crypto/rand to math/rand's global source, as this package used
+// before. It exists only for tests; the tokens are not reproducible
+// either, since rand.Seed has no effect on that source as of Go 1.24.
+// Production Clients should never use it, since math/rand tokens are
+// neither unpredictable nor collision-resistant under concurrent use.
+func WithInsecureRandom() Option {
+	return func(c *Client) { c.insecureRandom = true }
+}
+
+// NewClient returns a Client backed by cfg.
+func NewClient(cfg aws.Config, opts ...Option) *Client {
+	c := &Client{db: dynamodb.NewFromConfig(cfg)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// newClientRequestToken returns a ClientRequestToken sized for
+// TransactWriteItems, honoring c's WithInsecureRandom option. DynamoDB's
+// BatchWriteItem API has no ClientRequestToken of its own; callers that
+// need idempotent retries should use TransactWriteItems instead.
+func (c *Client) newClientRequestToken() string {
+	return randString(clientRequestTokenLength, c.insecureRandom)
+}
+
+// marshalItem converts item into a dbitem ready for PutItem/PutTransactItem,
+// stripping empty attributes according to c.marshalOpts and item's own
+// `dynamodbav:",omitempty"`/`",allowempty"` tags.
+func (c *Client) marshalItem(item interface{}) (dbitem, error) {
+	kv, err := dbattribute.MarshalMap(item)
+	if err != nil {
+		return nil, fmt.Errorf("dynami: invalid item (%v)", err)
+	}
+
+	t := reflect.TypeOf(item)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	resolved, err := sc.ResolveFields(c.tagResolver(), t)
+	if err != nil {
+		return nil, fmt.Errorf("dynami: resolve schema (%v)", err)
+	}
+
+	fields := map[string]sc.Field{}
+	for _, f := range resolved {
+		if f.OmitEmpty || f.AllowEmpty {
+			fields[f.Name] = f
+		}
+	}
+
+	return removeEmptyAttr(dbitem(kv), c.marshalOpts, fields), nil
+}
+
+// PutItem writes item to tableName, using c's MarshalOptions to decide
+// which empty-looking attributes to strip before the call. cond, if
+// non-nil, supplies a ConditionExpression the put must satisfy.
+func (c *Client) PutItem(ctx context.Context, tableName string, item interface{}, cond *exp.ConditionBuilder) error {
+	if err := checkType(item, reflect.Struct); err != nil {
+		return err
+	}
+
+	kv, err := c.marshalItem(item)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(tableName),
+		Item:      kv,
+	}
+	if cond != nil {
+		expr, err := exp.NewBuilder().WithCondition(*cond).Build()
+		if err != nil {
+			return fmt.Errorf("dynami: build condition expression (%v)", err)
+		}
+		input.ConditionExpression = expr.Condition()
+		input.ExpressionAttributeNames = expr.Names()
+		input.ExpressionAttributeValues = expr.Values()
+	}
+
+	if _, err := c.db.PutItem(ctx, input); err != nil {
+		return fmt.Errorf("dynami: put item (%v)", err)
+	}
+	return nil
+}
+
+// GetItem reads the item keyed by key from tableName into out, which
+// must be a pointer to a struct. key is any struct or pointer-to-struct
+// whose primary key fields are populated; it need not be the same type
+// as out.
+func (c *Client) GetItem(ctx context.Context, tableName string, key interface{}, out interface{}) error {
+	if err := checkPtrType(out, reflect.Struct); err != nil {
+		return err
+	}
+
+	k, err := getPrimaryKey(c.tagResolver(), key)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key:       k.value,
+	})
+	if err != nil {
+		return fmt.Errorf("dynami: get item (%v)", err)
+	}
+	if res.Item == nil {
+		return fmt.Errorf("dynami: item not found")
+	}
+
+	if err := dbattribute.UnmarshalMap(res.Item, out); err != nil {
+		return fmt.Errorf("dynami: unmarshal item (%v)", err)
+	}
+	return nil
+}
+
+// DeleteItem deletes the item keyed by key from tableName. cond, if
+// non-nil, supplies a ConditionExpression the delete must satisfy.
+func (c *Client) DeleteItem(ctx context.Context, tableName string, key interface{}, cond *exp.ConditionBuilder) error {
+	k, err := getPrimaryKey(c.tagResolver(), key)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(tableName),
+		Key:       k.value,
+	}
+	if cond != nil {
+		expr, err := exp.NewBuilder().WithCondition(*cond).Build()
+		if err != nil {
+			return fmt.Errorf("dynami: build condition expression (%v)", err)
+		}
+		input.ConditionExpression = expr.Condition()
+		input.ExpressionAttributeNames = expr.Names()
+		input.ExpressionAttributeValues = expr.Values()
+	}
+
+	if _, err := c.db.DeleteItem(ctx, input); err != nil {
+		return fmt.Errorf("dynami: delete item (%v)", err)
+	}
+	return nil
+}
+
+// UpdateItem applies update to the item keyed by key in tableName.
+func (c *Client) UpdateItem(ctx context.Context, tableName string, key interface{}, update exp.UpdateBuilder) error {
+	k, err := getPrimaryKey(c.tagResolver(), key)
+	if err != nil {
+		return err
+	}
+
+	expr, err := exp.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return fmt.Errorf("dynami: build update expression (%v)", err)
+	}
+
+	_, err = c.db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(tableName),
+		Key:                       k.value,
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if err != nil {
+		return fmt.Errorf("dynami: update item (%v)", err)
+	}
+	return nil
+}
+
+// Query runs keyCond against tableName, optionally narrowed by filter,
+// and unmarshals the matching items into out, a pointer to a slice.
+// indexName selects a global or local secondary index; pass "" to query
+// the table's primary key instead.
+func (c *Client) Query(
+	ctx context.Context,
+	tableName, indexName string,
+	keyCond exp.KeyConditionBuilder,
+	filter *exp.ConditionBuilder,
+	out interface{},
+) error {
+	b := exp.NewBuilder().WithKeyCondition(keyCond)
+	if filter != nil {
+		b = b.WithFilter(*filter)
+	}
+
+	expr, err := b.Build()
+	if err != nil {
+		return fmt.Errorf("dynami: build query expression (%v)", err)
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(tableName),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+	if indexName != "" {
+		input.IndexName = aws.String(indexName)
+	}
+
+	res, err := c.db.Query(ctx, input)
+	if err != nil {
+		return fmt.Errorf("dynami: query (%v)", err)
+	}
+
+	if err := dbattribute.UnmarshalListOfMaps(res.Items, out); err != nil {
+		return fmt.Errorf("dynami: unmarshal items (%v)", err)
+	}
+	return nil
+}
+
+// QueryKey runs a key-only query against tableName, deriving its
+// KeyConditionExpression from key's own `dynami` struct tags instead of
+// requiring the caller to build one with exp.Key. key may satisfy
+// either the primary key or any single secondary index (GetItem, by
+// contrast, only accepts the primary key); the matching index, if any,
+// is queried automatically. Key attribute names are escaped with
+// exp.NameNoDotSplit, since a table's key schema names are never
+// document paths.
+func (c *Client) QueryKey(ctx context.Context, tableName string, key interface{}, filter *exp.ConditionBuilder, out interface{}) error {
+	k, err := getKey(c.tagResolver(), key)
+	if err != nil {
+		return err
+	}
+
+	keyCond, err := k.condition()
+	if err != nil {
+		return err
+	}
+
+	return c.Query(ctx, tableName, k.indexName, keyCond, filter, out)
+}
+
+// Scan reads every item in tableName, optionally narrowed by filter, and
+// unmarshals the matching items into out, a pointer to a slice.
+func (c *Client) Scan(ctx context.Context, tableName string, filter *exp.ConditionBuilder, out interface{}) error {
+	b := exp.NewBuilder()
+	if filter != nil {
+		b = b.WithFilter(*filter)
+	}
+
+	expr, err := b.Build()
+	if err != nil {
+		return fmt.Errorf("dynami: build scan expression (%v)", err)
+	}
+
+	res, err := c.db.Scan(ctx, &dynamodb.ScanInput{
+		TableName:                 aws.String(tableName),
+		FilterExpression:          expr.Filter(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if err != nil {
+		return fmt.Errorf("dynami: scan (%v)", err)
+	}
+
+	if err := dbattribute.UnmarshalListOfMaps(res.Items, out); err != nil {
+		return fmt.Errorf("dynami: unmarshal items (%v)", err)
+	}
+	return nil
+}
+
+// TransactItem is one operation within a TransactWriteItems call. Build
+// one with PutTransactItem, UpdateTransactItem or DeleteTransactItem.
+type TransactItem struct {
+	put    *dbtypes.Put
+	update *dbtypes.Update
+	del    *dbtypes.Delete
+}
+
+// PutTransactItem returns a TransactItem that writes item to tableName,
+// using c's MarshalOptions to decide which empty-looking attributes to
+// strip before the call.
+func (c *Client) PutTransactItem(tableName string, item interface{}) (TransactItem, error) {
+	if err := checkType(item, reflect.Struct); err != nil {
+		return TransactItem{}, err
+	}
+
+	kv, err := c.marshalItem(item)
+	if err != nil {
+		return TransactItem{}, err
+	}
+
+	return TransactItem{put: &dbtypes.Put{
+		TableName: aws.String(tableName),
+		Item:      kv,
+	}}, nil
+}
+
+// UpdateTransactItem returns a TransactItem that applies update to the
+// item keyed by key in tableName.
+func (c *Client) UpdateTransactItem(tableName string, key interface{}, update exp.UpdateBuilder) (TransactItem, error) {
+	k, err := getPrimaryKey(c.tagResolver(), key)
+	if err != nil {
+		return TransactItem{}, err
+	}
+
+	expr, err := exp.NewBuilder().WithUpdate(update).Build()
+	if err != nil {
+		return TransactItem{}, fmt.Errorf("dynami: build update expression (%v)", err)
+	}
+
+	return TransactItem{update: &dbtypes.Update{
+		TableName:                 aws.String(tableName),
+		Key:                       k.value,
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}}, nil
+}
+
+// DeleteTransactItem returns a TransactItem that deletes the item keyed
+// by key from tableName.
+func (c *Client) DeleteTransactItem(tableName string, key interface{}) (TransactItem, error) {
+	k, err := getPrimaryKey(c.tagResolver(), key)
+	if err != nil {
+		return TransactItem{}, err
+	}
+
+	return TransactItem{del: &dbtypes.Delete{
+		TableName: aws.String(tableName),
+		Key:       k.value,
+	}}, nil
+}
+
+func (t TransactItem) build() dbtypes.TransactWriteItem {
+	return dbtypes.TransactWriteItem{
+		Put:    t.put,
+		Update: t.update,
+		Delete: t.del,
+	}
+}
+
+// TransactWriteItems atomically applies items. The ClientRequestToken is
+// generated with c.newClientRequestToken, so the SDK's idempotent-retry
+// behavior has a stable token to key off of across retries of the same
+// logical request.
+func (c *Client) TransactWriteItems(ctx context.Context, items []TransactItem) error {
+	writeItems := make([]dbtypes.TransactWriteItem, len(items))
+	for i, item := range items {
+		writeItems[i] = item.build()
+	}
+
+	_, err := c.db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems:      writeItems,
+		ClientRequestToken: aws.String(c.newClientRequestToken()),
+	})
+	if err != nil {
+		return fmt.Errorf("dynami: transact write items (%v)", err)
+	}
+	return nil
+}
+
+// WriteBatchItem is one write within a BatchWriteItem call. Build one
+// with PutBatchItem or DeleteBatchItem. Unlike TransactItem, it has no
+// update variant, since BatchWriteItem's API only supports puts and
+// deletes.
+type WriteBatchItem struct {
+	tableName string
+	put       dbitem
+	delKey    dbitem
+}
+
+// PutBatchItem returns a WriteBatchItem that writes item to tableName,
+// using c's MarshalOptions to decide which empty-looking attributes to
+// strip before the call.
+func (c *Client) PutBatchItem(tableName string, item interface{}) (WriteBatchItem, error) {
+	if err := checkType(item, reflect.Struct); err != nil {
+		return WriteBatchItem{}, err
+	}
+
+	kv, err := c.marshalItem(item)
+	if err != nil {
+		return WriteBatchItem{}, err
+	}
+
+	return WriteBatchItem{tableName: tableName, put: kv}, nil
+}
+
+// DeleteBatchItem returns a WriteBatchItem that deletes the item keyed
+// by key from tableName.
+func (c *Client) DeleteBatchItem(tableName string, key interface{}) (WriteBatchItem, error) {
+	k, err := getPrimaryKey(c.tagResolver(), key)
+	if err != nil {
+		return WriteBatchItem{}, err
+	}
+
+	return WriteBatchItem{tableName: tableName, delKey: k.value}, nil
+}
+
+func (w WriteBatchItem) build() dbtypes.WriteRequest {
+	if w.put != nil {
+		return dbtypes.WriteRequest{PutRequest: &dbtypes.PutRequest{Item: w.put}}
+	}
+	return dbtypes.WriteRequest{DeleteRequest: &dbtypes.DeleteRequest{Key: w.delKey}}
+}
+
+// BatchWriteItem writes and deletes items, grouped by the tableName each
+// was built with. Unlike TransactWriteItems, BatchWriteItem's API has no
+// ClientRequestToken, so the SDK can't deduplicate a retried batch; a
+// partial failure comes back from DynamoDB as UnprocessedItems instead of
+// rolling back, which this method reports as an error without retrying
+// them, so callers that need all-or-nothing or idempotent retries should
+// use TransactWriteItems instead.
+func (c *Client) BatchWriteItem(ctx context.Context, items []WriteBatchItem) error {
+	reqs := map[string][]dbtypes.WriteRequest{}
+	for _, item := range items {
+		reqs[item.tableName] = append(reqs[item.tableName], item.build())
+	}
+
+	res, err := c.db.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+		RequestItems: reqs,
+	})
+	if err != nil {
+		return fmt.Errorf("dynami: batch write item (%v)", err)
+	}
+	if len(res.UnprocessedItems) > 0 {
+		return fmt.Errorf("dynami: batch write item (unprocessed items in %v table(s))", len(res.UnprocessedItems))
+	}
+	return nil
+}
+
+// BatchGetItem reads the items keyed by keys from tableName into out, a
+// pointer to a slice, in a single call.
+func (c *Client) BatchGetItem(ctx context.Context, tableName string, keys []interface{}, out interface{}) error {
+	kv := make([]map[string]dbtypes.AttributeValue, len(keys))
+	for i, key := range keys {
+		k, err := getPrimaryKey(c.tagResolver(), key)
+		if err != nil {
+			return err
+		}
+		kv[i] = k.value
+	}
+
+	res, err := c.db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]dbtypes.KeysAndAttributes{
+			tableName: {Keys: kv},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("dynami: batch get item (%v)", err)
+	}
+
+	if err := dbattribute.UnmarshalListOfMaps(res.Responses[tableName], out); err != nil {
+		return fmt.Errorf("dynami: unmarshal items (%v)", err)
+	}
+	return nil
+}
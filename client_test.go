@@ -0,0 +1,152 @@
+package dynami
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	sc "github.com/robskie/dynami/schema"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestClientPutItemRejectsNonStruct(t *testing.T) {
+	c := NewClient(aws.Config{})
+
+	err := c.PutItem(context.Background(), "table", "not-a-struct", nil)
+	if err == nil {
+		t.Fatal("expected an error for a non-struct item, got nil")
+	}
+}
+
+func TestClientGetItemRejectsNonStructPtrOut(t *testing.T) {
+	c := NewClient(aws.Config{})
+
+	var out string
+	err := c.GetItem(context.Background(), "table", struct{ ID string }{ID: "1"}, &out)
+	if err == nil {
+		t.Fatal("expected an error for a non-struct-pointer out, got nil")
+	}
+}
+
+// TestWithInsecureRandomIsPerClient guards against WithInsecureRandom
+// regressing into a package-level switch: a Client built without it must
+// keep generating crypto/rand tokens even while another Client in the
+// same process has it set.
+func TestWithInsecureRandomIsPerClient(t *testing.T) {
+	secure := NewClient(aws.Config{})
+	insecure := NewClient(aws.Config{}, WithInsecureRandom())
+
+	if secure.insecureRandom {
+		t.Fatal("expected secure client to have insecureRandom == false")
+	}
+	if !insecure.insecureRandom {
+		t.Fatal("expected insecure client to have insecureRandom == true")
+	}
+
+	if len(secure.newClientRequestToken()) != clientRequestTokenLength {
+		t.Fatalf("expected token of length %v", clientRequestTokenLength)
+	}
+}
+
+type marshalOptionsItem struct {
+	ID   string `dynamodbav:"id" dynami:"hash"`
+	Name string `dynamodbav:"name"`
+}
+
+// TestClientMarshalItemUsesMarshalOptions guards the MarshalOptions ->
+// removeEmptyAttr wiring: an empty-string attribute must be stripped by
+// default, and kept when the Client is built with
+// WithMarshalOptions(MarshalOptions{AllowEmptyString: true}).
+func TestClientMarshalItemUsesMarshalOptions(t *testing.T) {
+	item := marshalOptionsItem{ID: "1"}
+
+	c := NewClient(aws.Config{})
+	kv, err := c.marshalItem(&item)
+	if err != nil {
+		t.Fatalf("marshalItem: %v", err)
+	}
+	if _, ok := kv["name"]; ok {
+		t.Fatalf("expected empty name attribute to be stripped by default, got %+v", kv)
+	}
+
+	allowEmpty := NewClient(aws.Config{}, WithMarshalOptions(MarshalOptions{AllowEmptyString: true}))
+	kv, err = allowEmpty.marshalItem(&item)
+	if err != nil {
+		t.Fatalf("marshalItem: %v", err)
+	}
+	if _, ok := kv["name"]; !ok {
+		t.Fatalf("expected empty name attribute to be kept with AllowEmptyString, got %+v", kv)
+	}
+}
+
+// allowEmptyResolver is a TagResolver stub that marks every field
+// AllowEmpty, letting TestWithTagResolverIsPerClient tell its effect
+// apart from the default resolver's without depending on MarshalOptions.
+type allowEmptyResolver struct{}
+
+func (allowEmptyResolver) Resolve(t reflect.Type) ([]sc.Field, error) {
+	fields, err := sc.ResolveFields(sc.DefaultResolver(), t)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]sc.Field, len(fields))
+	for i, f := range fields {
+		f.AllowEmpty = true
+		out[i] = f
+	}
+	return out, nil
+}
+
+// TestWithTagResolverIsPerClient guards the WithTagResolver option: a
+// Client built with a custom resolver must use it instead of
+// schema.DefaultResolver, and a Client without one must be unaffected by
+// it.
+func TestWithTagResolverIsPerClient(t *testing.T) {
+	item := marshalOptionsItem{ID: "1"}
+
+	def := NewClient(aws.Config{})
+	kv, err := def.marshalItem(&item)
+	if err != nil {
+		t.Fatalf("marshalItem: %v", err)
+	}
+	if _, ok := kv["name"]; ok {
+		t.Fatalf("expected empty name attribute to be stripped by default resolver, got %+v", kv)
+	}
+
+	custom := NewClient(aws.Config{}, WithTagResolver(allowEmptyResolver{}))
+	kv, err = custom.marshalItem(&item)
+	if err != nil {
+		t.Fatalf("marshalItem: %v", err)
+	}
+	if _, ok := kv["name"]; !ok {
+		t.Fatalf("expected empty name attribute to be kept by allowEmptyResolver, got %+v", kv)
+	}
+}
+
+// TestWriteBatchItemBuildsPutOrDelete guards WriteBatchItem.build, which
+// BatchWriteItem relies on to turn a PutBatchItem/DeleteBatchItem into
+// the dynamodb.types.WriteRequest the batch request actually sends.
+func TestWriteBatchItemBuildsPutOrDelete(t *testing.T) {
+	item := marshalOptionsItem{ID: "1"}
+	c := NewClient(aws.Config{})
+
+	put, err := c.PutBatchItem("table", &item)
+	if err != nil {
+		t.Fatalf("PutBatchItem: %v", err)
+	}
+	wr := put.build()
+	if wr.PutRequest == nil || wr.DeleteRequest != nil {
+		t.Fatalf("expected a PutRequest only, got %+v", wr)
+	}
+
+	del, err := c.DeleteBatchItem("table", &item)
+	if err != nil {
+		t.Fatalf("DeleteBatchItem: %v", err)
+	}
+	wr = del.build()
+	if wr.DeleteRequest == nil || wr.PutRequest != nil {
+		t.Fatalf("expected a DeleteRequest only, got %+v", wr)
+	}
+}
@@ -0,0 +1,204 @@
+package expression
+
+import "fmt"
+
+type conditionMode int
+
+const (
+	equalCond conditionMode = iota
+	notEqualCond
+	lessThanCond
+	lessThanEqualCond
+	greaterThanCond
+	greaterThanEqualCond
+	betweenCond
+	beginsWithCond
+	containsCond
+	attrExistsCond
+	attrNotExistsCond
+	andCond
+	orCond
+	notCond
+)
+
+// ConditionBuilder represents a ConditionExpression or FilterExpression.
+type ConditionBuilder struct {
+	mode       conditionMode
+	name       NameBuilder
+	values     []ValueBuilder
+	conditions []ConditionBuilder
+}
+
+// Equal builds a ConditionBuilder asserting name equals v.
+func (n NameBuilder) Equal(v ValueBuilder) ConditionBuilder {
+	return ConditionBuilder{mode: equalCond, name: n, values: []ValueBuilder{v}}
+}
+
+// NotEqual builds a ConditionBuilder asserting name does not equal v.
+func (n NameBuilder) NotEqual(v ValueBuilder) ConditionBuilder {
+	return ConditionBuilder{mode: notEqualCond, name: n, values: []ValueBuilder{v}}
+}
+
+// LessThan builds a ConditionBuilder asserting name is less than v.
+func (n NameBuilder) LessThan(v ValueBuilder) ConditionBuilder {
+	return ConditionBuilder{mode: lessThanCond, name: n, values: []ValueBuilder{v}}
+}
+
+// LessThanEqual builds a ConditionBuilder asserting name is at most v.
+func (n NameBuilder) LessThanEqual(v ValueBuilder) ConditionBuilder {
+	return ConditionBuilder{mode: lessThanEqualCond, name: n, values: []ValueBuilder{v}}
+}
+
+// GreaterThan builds a ConditionBuilder asserting name is greater than v.
+func (n NameBuilder) GreaterThan(v ValueBuilder) ConditionBuilder {
+	return ConditionBuilder{mode: greaterThanCond, name: n, values: []ValueBuilder{v}}
+}
+
+// GreaterThanEqual builds a ConditionBuilder asserting name is at least v.
+func (n NameBuilder) GreaterThanEqual(v ValueBuilder) ConditionBuilder {
+	return ConditionBuilder{mode: greaterThanEqualCond, name: n, values: []ValueBuilder{v}}
+}
+
+// Between builds a ConditionBuilder asserting name is between lower and upper, inclusive.
+func (n NameBuilder) Between(lower, upper ValueBuilder) ConditionBuilder {
+	return ConditionBuilder{mode: betweenCond, name: n, values: []ValueBuilder{lower, upper}}
+}
+
+// BeginsWith builds a ConditionBuilder asserting name begins with prefix.
+func (n NameBuilder) BeginsWith(prefix string) ConditionBuilder {
+	return ConditionBuilder{mode: beginsWithCond, name: n, values: []ValueBuilder{Value(prefix)}}
+}
+
+// Contains builds a ConditionBuilder asserting name contains v, either as
+// a substring of a string attribute or an element of a set attribute.
+func (n NameBuilder) Contains(v interface{}) ConditionBuilder {
+	return ConditionBuilder{mode: containsCond, name: n, values: []ValueBuilder{Value(v)}}
+}
+
+// AttributeExists builds a ConditionBuilder asserting name is present on the item.
+func (n NameBuilder) AttributeExists() ConditionBuilder {
+	return ConditionBuilder{mode: attrExistsCond, name: n}
+}
+
+// AttributeNotExists builds a ConditionBuilder asserting name is absent from the item.
+func (n NameBuilder) AttributeNotExists() ConditionBuilder {
+	return ConditionBuilder{mode: attrNotExistsCond, name: n}
+}
+
+// And combines c with more, all of which must hold.
+func (c ConditionBuilder) And(more ...ConditionBuilder) ConditionBuilder {
+	return ConditionBuilder{mode: andCond, conditions: append([]ConditionBuilder{c}, more...)}
+}
+
+// Or combines c with more, at least one of which must hold.
+func (c ConditionBuilder) Or(more ...ConditionBuilder) ConditionBuilder {
+	return ConditionBuilder{mode: orCond, conditions: append([]ConditionBuilder{c}, more...)}
+}
+
+// Not negates c.
+func Not(c ConditionBuilder) ConditionBuilder {
+	return ConditionBuilder{mode: notCond, conditions: []ConditionBuilder{c}}
+}
+
+func (c ConditionBuilder) buildOperand(a *aliaser) (string, error) {
+	switch c.mode {
+	case equalCond:
+		return c.buildComparison(a, "=")
+	case notEqualCond:
+		return c.buildComparison(a, "<>")
+	case lessThanCond:
+		return c.buildComparison(a, "<")
+	case lessThanEqualCond:
+		return c.buildComparison(a, "<=")
+	case greaterThanCond:
+		return c.buildComparison(a, ">")
+	case greaterThanEqualCond:
+		return c.buildComparison(a, ">=")
+
+	case betweenCond:
+		name, err := c.name.buildOperand(a)
+		if err != nil {
+			return "", err
+		}
+		lower, err := c.values[0].buildOperand(a)
+		if err != nil {
+			return "", err
+		}
+		upper, err := c.values[1].buildOperand(a)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%v BETWEEN %v AND %v", name, lower, upper), nil
+
+	case beginsWithCond:
+		return c.buildFunction(a, "begins_with")
+	case containsCond:
+		return c.buildFunction(a, "contains")
+	case attrExistsCond:
+		name, err := c.name.buildOperand(a)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("attribute_exists(%v)", name), nil
+	case attrNotExistsCond:
+		name, err := c.name.buildOperand(a)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("attribute_not_exists(%v)", name), nil
+
+	case andCond:
+		return c.buildCombination(a, " AND ")
+	case orCond:
+		return c.buildCombination(a, " OR ")
+	case notCond:
+		inner, err := c.conditions[0].buildOperand(a)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(NOT %v)", inner), nil
+	}
+
+	return "", fmt.Errorf("expression: unknown condition")
+}
+
+func (c ConditionBuilder) buildComparison(a *aliaser, op string) (string, error) {
+	name, err := c.name.buildOperand(a)
+	if err != nil {
+		return "", err
+	}
+	value, err := c.values[0].buildOperand(a)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v %v %v", name, op, value), nil
+}
+
+func (c ConditionBuilder) buildFunction(a *aliaser, fn string) (string, error) {
+	name, err := c.name.buildOperand(a)
+	if err != nil {
+		return "", err
+	}
+	value, err := c.values[0].buildOperand(a)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v(%v, %v)", fn, name, value), nil
+}
+
+func (c ConditionBuilder) buildCombination(a *aliaser, sep string) (string, error) {
+	parts := make([]string, len(c.conditions))
+	for i, cond := range c.conditions {
+		s, err := cond.buildOperand(a)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = s
+	}
+
+	out := parts[0]
+	for _, p := range parts[1:] {
+		out = fmt.Sprintf("(%v)%v(%v)", out, sep, p)
+	}
+	return out, nil
+}
@@ -0,0 +1,274 @@
+// Package expression builds DynamoDB ConditionExpression, FilterExpression,
+// KeyConditionExpression, UpdateExpression and ProjectionExpression strings
+// together with their ExpressionAttributeNames/Values maps, modeled after
+// aws-sdk-go-v2's feature/dynamodb/expression package.
+package expression
+
+import (
+	"fmt"
+	"strings"
+
+	dbattribute "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// operand is anything that can render itself as part of an expression
+// string, recording any names/values it needs along the way.
+type operand interface {
+	buildOperand(a *aliaser) (string, error)
+}
+
+// aliaser assigns and collects the #name/:value placeholders referenced
+// by a single Expression.
+type aliaser struct {
+	names    map[string]string
+	nameIdx  int
+	values   map[string]types.AttributeValue
+	valueIdx int
+}
+
+func newAliaser() *aliaser {
+	return &aliaser{
+		names:  map[string]string{},
+		values: map[string]types.AttributeValue{},
+	}
+}
+
+// aliasPath renders path as a sequence of #name placeholders joined by
+// ".", one per document path segment, unless noDotSplit is set, in which
+// case the whole path is treated as a single top-level attribute name
+// even if it contains dots.
+func (a *aliaser) aliasPath(path string, noDotSplit bool) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("expression: empty name")
+	}
+
+	if noDotSplit {
+		return a.aliasSegment(path), nil
+	}
+
+	segments := strings.Split(path, ".")
+	placeholders := make([]string, len(segments))
+	for i, seg := range segments {
+		name, index := splitIndex(seg)
+		if name == "" {
+			return "", fmt.Errorf("expression: invalid name (%v)", path)
+		}
+		placeholders[i] = a.aliasSegment(name) + index
+	}
+
+	return strings.Join(placeholders, "."), nil
+}
+
+// splitIndex splits a path segment like "foo[0]" into its name ("foo")
+// and index suffix ("[0]"). Segments without an index are returned
+// unchanged with an empty suffix.
+func splitIndex(segment string) (name, index string) {
+	i := strings.IndexByte(segment, '[')
+	if i < 0 {
+		return segment, ""
+	}
+	return segment[:i], segment[i:]
+}
+
+func (a *aliaser) aliasSegment(name string) string {
+	ph := fmt.Sprintf("#%d", a.nameIdx)
+	a.nameIdx++
+	a.names[ph] = name
+	return ph
+}
+
+func (a *aliaser) aliasValue(av types.AttributeValue) string {
+	ph := fmt.Sprintf(":%d", a.valueIdx)
+	a.valueIdx++
+	a.values[ph] = av
+	return ph
+}
+
+// NameBuilder represents an item attribute name or document path.
+type NameBuilder struct {
+	path       string
+	noDotSplit bool
+}
+
+// Name builds a document path from a dotted attribute name, e.g.
+// "foo.bar[0]" addresses the first element of the "bar" list stored in
+// the "foo" map attribute.
+func Name(path string) NameBuilder {
+	return NameBuilder{path: path}
+}
+
+// NameNoDotSplit builds a reference to a single top-level attribute
+// whose name itself contains dots, e.g. "foo.bar" as one attribute
+// rather than a path into "foo". Use this for items whose keys are not
+// under the caller's control and may contain literal dots.
+func NameNoDotSplit(name string) NameBuilder {
+	return NameBuilder{path: name, noDotSplit: true}
+}
+
+func (n NameBuilder) buildOperand(a *aliaser) (string, error) {
+	return a.aliasPath(n.path, n.noDotSplit)
+}
+
+// ValueBuilder represents a literal value substituted into an expression.
+type ValueBuilder struct {
+	value interface{}
+	raw   types.AttributeValue
+}
+
+// Value builds an ExpressionAttributeValue from a Go value using the
+// same dynamodbav/json marshalling rules as items.
+func Value(value interface{}) ValueBuilder {
+	return ValueBuilder{value: value}
+}
+
+// RawValue builds an ExpressionAttributeValue from an already-marshalled
+// AttributeValue, letting callers reuse a value without round-tripping
+// it through the marshaller again.
+func RawValue(av types.AttributeValue) ValueBuilder {
+	return ValueBuilder{raw: av}
+}
+
+func (v ValueBuilder) buildOperand(a *aliaser) (string, error) {
+	av := v.raw
+	if av == nil {
+		var err error
+		av, err = dbattribute.Marshal(v.value)
+		if err != nil {
+			return "", fmt.Errorf("expression: invalid value (%v)", err)
+		}
+	}
+	return a.aliasValue(av), nil
+}
+
+// Expression holds the built strings and substitution maps for one or
+// more of ConditionExpression, FilterExpression, KeyConditionExpression,
+// UpdateExpression and ProjectionExpression.
+type Expression struct {
+	condition    string
+	filter       string
+	keyCondition string
+	update       string
+	projection   string
+
+	names  map[string]string
+	values map[string]types.AttributeValue
+}
+
+// Condition returns the built ConditionExpression, or nil if none was set.
+func (e Expression) Condition() *string { return nonEmpty(e.condition) }
+
+// Filter returns the built FilterExpression, or nil if none was set.
+func (e Expression) Filter() *string { return nonEmpty(e.filter) }
+
+// KeyCondition returns the built KeyConditionExpression, or nil if none was set.
+func (e Expression) KeyCondition() *string { return nonEmpty(e.keyCondition) }
+
+// Update returns the built UpdateExpression, or nil if none was set.
+func (e Expression) Update() *string { return nonEmpty(e.update) }
+
+// Projection returns the built ProjectionExpression, or nil if none was set.
+func (e Expression) Projection() *string { return nonEmpty(e.projection) }
+
+// Names returns the ExpressionAttributeNames referenced by this
+// Expression, ready to assign directly to a DynamoDB request's
+// ExpressionAttributeNames field.
+func (e Expression) Names() map[string]string { return e.names }
+
+// Values returns the ExpressionAttributeValues referenced by this Expression.
+func (e Expression) Values() map[string]types.AttributeValue { return e.values }
+
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// Builder assembles the expression clauses that apply to a single
+// request, sharing one set of name/value placeholders across all of them.
+type Builder struct {
+	condition    *ConditionBuilder
+	filter       *ConditionBuilder
+	keyCondition *KeyConditionBuilder
+	update       *UpdateBuilder
+	projection   *ProjectionBuilder
+}
+
+// NewBuilder returns an empty Builder.
+func NewBuilder() Builder {
+	return Builder{}
+}
+
+// WithCondition sets the builder's ConditionExpression.
+func (b Builder) WithCondition(c ConditionBuilder) Builder {
+	b.condition = &c
+	return b
+}
+
+// WithFilter sets the builder's FilterExpression.
+func (b Builder) WithFilter(c ConditionBuilder) Builder {
+	b.filter = &c
+	return b
+}
+
+// WithKeyCondition sets the builder's KeyConditionExpression.
+func (b Builder) WithKeyCondition(k KeyConditionBuilder) Builder {
+	b.keyCondition = &k
+	return b
+}
+
+// WithUpdate sets the builder's UpdateExpression.
+func (b Builder) WithUpdate(u UpdateBuilder) Builder {
+	b.update = &u
+	return b
+}
+
+// WithProjection sets the builder's ProjectionExpression.
+func (b Builder) WithProjection(p ProjectionBuilder) Builder {
+	b.projection = &p
+	return b
+}
+
+// Build resolves every clause set on b into a single Expression with a
+// shared set of ExpressionAttributeNames/Values.
+func (b Builder) Build() (Expression, error) {
+	a := newAliaser()
+	e := Expression{}
+
+	var err error
+	if b.condition != nil {
+		if e.condition, err = b.condition.buildOperand(a); err != nil {
+			return Expression{}, err
+		}
+	}
+	if b.filter != nil {
+		if e.filter, err = b.filter.buildOperand(a); err != nil {
+			return Expression{}, err
+		}
+	}
+	if b.keyCondition != nil {
+		if e.keyCondition, err = b.keyCondition.buildOperand(a); err != nil {
+			return Expression{}, err
+		}
+	}
+	if b.update != nil {
+		if e.update, err = b.update.buildExpression(a); err != nil {
+			return Expression{}, err
+		}
+	}
+	if b.projection != nil {
+		if e.projection, err = b.projection.buildOperand(a); err != nil {
+			return Expression{}, err
+		}
+	}
+
+	if len(a.names) > 0 {
+		e.names = a.names
+	}
+	if len(a.values) > 0 {
+		e.values = a.values
+	}
+
+	return e, nil
+}
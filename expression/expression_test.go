@@ -0,0 +1,51 @@
+package expression
+
+import "testing"
+
+// TestNamesMatchesExpressionAttributeNames guards Expression.Names()'s
+// return type: aws-sdk-go-v2's ExpressionAttributeNames field is
+// map[string]string, not map[string]*string, so Names() must return the
+// same shape or it can't be assigned directly to a request.
+func TestNamesMatchesExpressionAttributeNames(t *testing.T) {
+	expr, err := NewBuilder().
+		WithCondition(Name("foo").Equal(Value("bar"))).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	names := expr.Names()
+	if names["#0"] != "foo" {
+		t.Fatalf("expected #0 to alias \"foo\", got %+v", names)
+	}
+}
+
+// TestNameNoDotSplitIsOneSegment guards the feature this request calls
+// critical: a dotted attribute name passed to NameNoDotSplit must alias
+// as one literal name, unlike Name, which splits the same string into a
+// two-segment document path.
+func TestNameNoDotSplitIsOneSegment(t *testing.T) {
+	expr, err := NewBuilder().
+		WithCondition(NameNoDotSplit("a.b").Equal(Value("x"))).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	names := expr.Names()
+	if len(names) != 1 || names["#0"] != "a.b" {
+		t.Fatalf("expected a single placeholder aliasing literal \"a.b\", got %+v", names)
+	}
+
+	pathExpr, err := NewBuilder().
+		WithCondition(Name("a.b").Equal(Value("x"))).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	pathNames := pathExpr.Names()
+	if len(pathNames) != 2 || pathNames["#0"] != "a" || pathNames["#1"] != "b" {
+		t.Fatalf("expected Name to split \"a.b\" into two path segments, got %+v", pathNames)
+	}
+}
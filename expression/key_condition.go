@@ -0,0 +1,81 @@
+package expression
+
+import "fmt"
+
+// KeyConditionBuilder represents a KeyConditionExpression. It is kept
+// distinct from ConditionBuilder because DynamoDB only allows a narrow
+// subset of operators (and no Or/Not) on key conditions.
+type KeyConditionBuilder struct {
+	partition ConditionBuilder
+	sortCond  *ConditionBuilder
+}
+
+// Key begins a KeyConditionBuilder for the given key attribute name.
+func Key(name NameBuilder) KeyBuilder {
+	return KeyBuilder{name: name}
+}
+
+// KeyBuilder is an intermediate value produced by Key, used to build the
+// single equality or range condition allowed on one key attribute.
+type KeyBuilder struct {
+	name NameBuilder
+}
+
+// Equal builds a key condition asserting the key equals v.
+func (k KeyBuilder) Equal(v ValueBuilder) KeyConditionBuilder {
+	return KeyConditionBuilder{partition: k.name.Equal(v)}
+}
+
+// LessThan builds a key condition asserting the key is less than v.
+func (k KeyBuilder) LessThan(v ValueBuilder) KeyConditionBuilder {
+	return KeyConditionBuilder{partition: k.name.LessThan(v)}
+}
+
+// LessThanEqual builds a key condition asserting the key is at most v.
+func (k KeyBuilder) LessThanEqual(v ValueBuilder) KeyConditionBuilder {
+	return KeyConditionBuilder{partition: k.name.LessThanEqual(v)}
+}
+
+// GreaterThan builds a key condition asserting the key is greater than v.
+func (k KeyBuilder) GreaterThan(v ValueBuilder) KeyConditionBuilder {
+	return KeyConditionBuilder{partition: k.name.GreaterThan(v)}
+}
+
+// GreaterThanEqual builds a key condition asserting the key is at least v.
+func (k KeyBuilder) GreaterThanEqual(v ValueBuilder) KeyConditionBuilder {
+	return KeyConditionBuilder{partition: k.name.GreaterThanEqual(v)}
+}
+
+// Between builds a key condition asserting the key is between lower and upper, inclusive.
+func (k KeyBuilder) Between(lower, upper ValueBuilder) KeyConditionBuilder {
+	return KeyConditionBuilder{partition: k.name.Between(lower, upper)}
+}
+
+// BeginsWith builds a key condition asserting the key begins with prefix.
+// Valid only on a sort key.
+func (k KeyBuilder) BeginsWith(prefix string) KeyConditionBuilder {
+	return KeyConditionBuilder{partition: k.name.BeginsWith(prefix)}
+}
+
+// KeyAnd combines a partition key condition with a sort key condition.
+// DynamoDB allows exactly one condition per key, so both part and sort
+// must each reference a different key attribute.
+func KeyAnd(part, sort KeyConditionBuilder) KeyConditionBuilder {
+	return KeyConditionBuilder{partition: part.partition, sortCond: &sort.partition}
+}
+
+func (k KeyConditionBuilder) buildOperand(a *aliaser) (string, error) {
+	partition, err := k.partition.buildOperand(a)
+	if err != nil {
+		return "", err
+	}
+	if k.sortCond == nil {
+		return partition, nil
+	}
+
+	sort, err := k.sortCond.buildOperand(a)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("(%v) AND (%v)", partition, sort), nil
+}
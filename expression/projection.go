@@ -0,0 +1,25 @@
+package expression
+
+import "strings"
+
+// ProjectionBuilder represents a ProjectionExpression.
+type ProjectionBuilder struct {
+	names []NameBuilder
+}
+
+// NamesList builds a ProjectionBuilder listing the given attribute names/paths.
+func NamesList(names ...NameBuilder) ProjectionBuilder {
+	return ProjectionBuilder{names: names}
+}
+
+func (p ProjectionBuilder) buildOperand(a *aliaser) (string, error) {
+	parts := make([]string, len(p.names))
+	for i, n := range p.names {
+		s, err := n.buildOperand(a)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, ", "), nil
+}
@@ -0,0 +1,85 @@
+package expression
+
+import (
+	"fmt"
+	"strings"
+)
+
+type updateClause struct {
+	action string // SET, REMOVE, ADD or DELETE
+	name   NameBuilder
+	value  *ValueBuilder // nil for REMOVE
+}
+
+// UpdateBuilder represents an UpdateExpression.
+type UpdateBuilder struct {
+	clauses []updateClause
+}
+
+// Set adds a "SET name = v" clause.
+func (u UpdateBuilder) Set(name NameBuilder, v ValueBuilder) UpdateBuilder {
+	u.clauses = append(u.clauses, updateClause{action: "SET", name: name, value: &v})
+	return u
+}
+
+// Remove adds a "REMOVE name" clause.
+func (u UpdateBuilder) Remove(name NameBuilder) UpdateBuilder {
+	u.clauses = append(u.clauses, updateClause{action: "REMOVE", name: name})
+	return u
+}
+
+// Add adds an "ADD name v" clause, incrementing a number or adding
+// elements to a set.
+func (u UpdateBuilder) Add(name NameBuilder, v ValueBuilder) UpdateBuilder {
+	u.clauses = append(u.clauses, updateClause{action: "ADD", name: name, value: &v})
+	return u
+}
+
+// Delete adds a "DELETE name v" clause, removing elements from a set.
+func (u UpdateBuilder) Delete(name NameBuilder, v ValueBuilder) UpdateBuilder {
+	u.clauses = append(u.clauses, updateClause{action: "DELETE", name: name, value: &v})
+	return u
+}
+
+func (u UpdateBuilder) buildExpression(a *aliaser) (string, error) {
+	grouped := map[string][]string{}
+	order := []string{"SET", "REMOVE", "ADD", "DELETE"}
+
+	for _, c := range u.clauses {
+		name, err := c.name.buildOperand(a)
+		if err != nil {
+			return "", err
+		}
+
+		var clause string
+		switch c.action {
+		case "SET":
+			value, err := c.value.buildOperand(a)
+			if err != nil {
+				return "", err
+			}
+			clause = fmt.Sprintf("%v = %v", name, value)
+		case "REMOVE":
+			clause = name
+		case "ADD", "DELETE":
+			value, err := c.value.buildOperand(a)
+			if err != nil {
+				return "", err
+			}
+			clause = fmt.Sprintf("%v %v", name, value)
+		}
+
+		grouped[c.action] = append(grouped[c.action], clause)
+	}
+
+	var sections []string
+	for _, action := range order {
+		clauses, ok := grouped[action]
+		if !ok {
+			continue
+		}
+		sections = append(sections, fmt.Sprintf("%v %v", action, strings.Join(clauses, ", ")))
+	}
+
+	return strings.Join(sections, " "), nil
+}
@@ -0,0 +1,235 @@
+// Package schema resolves a Go struct type into the DynamoDB attribute
+// names and key structure dynami needs to marshal, unmarshal and key
+// items, based on the struct's `dynamodbav`, `json` and `dynami` tags.
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Field describes one resolved, flattened struct field: its DynamoDB
+// attribute name and the reflect index path used to reach it, which may
+// pass through one or more embedded structs.
+type Field struct {
+	Name  string
+	Index []int
+	Type  reflect.Type
+
+	// OmitEmpty is set by a `dynamodbav:",omitempty"` tag, marking the
+	// field as always subject to empty-value stripping regardless of a
+	// Client's MarshalOptions.
+	OmitEmpty bool
+
+	// AllowEmpty is set by a `dynamodbav:",allowempty"` tag, marking the
+	// field as exempt from empty-value stripping regardless of a
+	// Client's MarshalOptions.
+	AllowEmpty bool
+}
+
+// TagResolver maps a struct type to the Fields DynamoDB should see. The
+// default implementation flattens embedded structs the way encoding/json
+// does and prefers the dynamodbav tag over json. Register a different
+// TagResolver with SetDefaultResolver, or pass one explicitly to
+// ResolveFields/GetSchemaWithResolver, to change that behavior.
+type TagResolver interface {
+	Resolve(t reflect.Type) ([]Field, error)
+}
+
+var (
+	mu              sync.RWMutex
+	defaultResolver TagResolver = tagResolver{}
+)
+
+// SetDefaultResolver replaces the TagResolver used by FieldsOf and
+// GetSchema when none is supplied explicitly.
+func SetDefaultResolver(r TagResolver) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultResolver = r
+}
+
+// DefaultResolver returns the package's current default TagResolver.
+func DefaultResolver() TagResolver {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultResolver
+}
+
+type resolverKey struct {
+	resolver TagResolver
+	typ      reflect.Type
+}
+
+var (
+	fieldCacheMu sync.RWMutex
+	fieldCache   = map[resolverKey][]Field{}
+)
+
+// FieldsOf returns t's resolved, flattened fields using the default
+// TagResolver. It returns an error if the type can't be resolved, e.g. a
+// field's dynamodbav and json tags disagree.
+func FieldsOf(t reflect.Type) ([]Field, error) {
+	return ResolveFields(DefaultResolver(), t)
+}
+
+// ResolveFields returns t's resolved, flattened fields using r, caching
+// the result per (r, t) pair so repeated lookups avoid re-walking the
+// type with reflection.
+func ResolveFields(r TagResolver, t reflect.Type) ([]Field, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	key := resolverKey{resolver: r, typ: t}
+
+	fieldCacheMu.RLock()
+	fields, ok := fieldCache[key]
+	fieldCacheMu.RUnlock()
+	if ok {
+		return fields, nil
+	}
+
+	fields, err := r.Resolve(t)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldCacheMu.Lock()
+	fieldCache[key] = fields
+	fieldCacheMu.Unlock()
+
+	return fields, nil
+}
+
+// tagResolver is the default TagResolver.
+type tagResolver struct{}
+
+func (tagResolver) Resolve(t reflect.Type) ([]Field, error) {
+	return resolveFields(t, nil)
+}
+
+func resolveFields(t reflect.Type, index []int) ([]Field, error) {
+	var fields []Field
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		// An anonymous struct field with no tag name of its own is
+		// flattened depth-first, same as encoding/json. A field only
+		// counts as untagged here if neither dynamodbav nor json names
+		// it: fieldName's default case falls back to f.Name, which is
+		// never "", so that can't be used to detect this.
+		if f.Anonymous && ft.Kind() == reflect.Struct && !hasTagName(f) {
+			embedded, err := resolveFields(ft, fieldIndex)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, embedded...)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+
+		name, omit, err := fieldName(f)
+		if err != nil {
+			return nil, err
+		}
+		if omit {
+			continue
+		}
+
+		omitEmpty, allowEmpty := fieldEmptyOptions(f)
+		fields = append(fields, Field{
+			Name:       name,
+			Index:      fieldIndex,
+			Type:       f.Type,
+			OmitEmpty:  omitEmpty,
+			AllowEmpty: allowEmpty,
+		})
+	}
+
+	return fields, nil
+}
+
+// fieldEmptyOptions reads the "omitempty"/"allowempty" options off f's
+// dynamodbav tag, e.g. `dynamodbav:"name,omitempty"`. Unlike the name
+// itself, these are dynamodbav-only; json's "omitempty" means something
+// different (omit the Go zero value) and isn't read here.
+func fieldEmptyOptions(f reflect.StructField) (omitEmpty, allowEmpty bool) {
+	tag := f.Tag.Get("dynamodbav")
+	if tag == "" {
+		return false, false
+	}
+
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "omitempty":
+			omitEmpty = true
+		case "allowempty":
+			allowEmpty = true
+		}
+	}
+	return omitEmpty, allowEmpty
+}
+
+// fieldName resolves f's DynamoDB attribute name, preferring the
+// dynamodbav tag over json when both are present. Silently preferring
+// one used to hide real bugs where the two tags disagreed, so this
+// returns a conflict error instead.
+func fieldName(f reflect.StructField) (name string, omit bool, err error) {
+	avTag, hasAV := f.Tag.Lookup("dynamodbav")
+	jsonTag, hasJSON := f.Tag.Lookup("json")
+
+	avName := firstTagPart(avTag)
+	jsonName := firstTagPart(jsonTag)
+
+	if hasAV && hasJSON && avName != "" && jsonName != "" &&
+		avName != "-" && jsonName != "-" && avName != jsonName {
+
+		return "", false, fmt.Errorf(
+			"schema: field %v has conflicting dynamodbav (%v) and json (%v) names",
+			f.Name, avName, jsonName,
+		)
+	}
+
+	switch {
+	case avName == "-":
+		return "", true, nil
+	case avName != "":
+		return avName, false, nil
+	case jsonName == "-":
+		return "", true, nil
+	case jsonName != "":
+		return jsonName, false, nil
+	default:
+		return f.Name, false, nil
+	}
+}
+
+// hasTagName reports whether f carries an explicit dynamodbav or json
+// name, including "-" to skip the field entirely. An anonymous struct
+// field is only flattened when this is false.
+func hasTagName(f reflect.StructField) bool {
+	avName := firstTagPart(f.Tag.Get("dynamodbav"))
+	jsonName := firstTagPart(f.Tag.Get("json"))
+	return avName != "" || jsonName != ""
+}
+
+func firstTagPart(tag string) string {
+	if tag == "" {
+		return ""
+	}
+	return strings.Split(tag, ",")[0]
+}
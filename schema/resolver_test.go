@@ -0,0 +1,83 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+type embeddedInner struct {
+	Foo string `dynamodbav:"foo"`
+}
+
+type embeddedOuter struct {
+	embeddedInner
+	Bar string `dynamodbav:"bar"`
+}
+
+type namedEmbed struct {
+	Foo string `dynamodbav:"foo"`
+}
+
+type conflictingTagsItem struct {
+	ID   string `dynamodbav:"id" dynami:"hash"`
+	Name string `dynamodbav:"name" json:"other_name"`
+}
+
+type outerWithNamedEmbed struct {
+	Inner namedEmbed `dynamodbav:"inner"`
+	Bar   string     `dynamodbav:"bar"`
+}
+
+// TestResolveFieldsFlattensUntaggedEmbed guards the bug where an
+// anonymous struct field was never flattened: fieldName's default case
+// returns f.Name (never ""), so a check for name == "" could never
+// detect an untagged embedded field.
+func TestResolveFieldsFlattensUntaggedEmbed(t *testing.T) {
+	fields, err := ResolveFields(tagResolver{}, reflect.TypeOf(embeddedOuter{}))
+	if err != nil {
+		t.Fatalf("ResolveFields: %v", err)
+	}
+
+	names := map[string]bool{}
+	for _, f := range fields {
+		names[f.Name] = true
+	}
+
+	if names["Inner"] || names["embeddedInner"] {
+		t.Fatalf("expected embeddedInner to be flattened, got fields %+v", fields)
+	}
+	if !names["foo"] || !names["bar"] {
+		t.Fatalf("expected flattened foo/bar fields, got %+v", fields)
+	}
+}
+
+// TestResolveFieldsKeepsNamedStructField guards against over-flattening:
+// a struct field with its own dynamodbav name (even a non-anonymous one
+// that happens to have a struct type) must stay nested, not flattened.
+func TestResolveFieldsKeepsNamedStructField(t *testing.T) {
+	fields, err := ResolveFields(tagResolver{}, reflect.TypeOf(outerWithNamedEmbed{}))
+	if err != nil {
+		t.Fatalf("ResolveFields: %v", err)
+	}
+
+	names := map[string]bool{}
+	for _, f := range fields {
+		names[f.Name] = true
+	}
+
+	if !names["inner"] || !names["bar"] {
+		t.Fatalf("expected inner/bar fields, got %+v", fields)
+	}
+}
+
+// TestFieldsOfReturnsConflictError guards against FieldsOf (and, by
+// extension, GetSchema) swallowing fieldName's tag-conflict error: both
+// used to substitute a nil/empty result on any resolution error, which
+// silently broke key resolution for every field of the type, not just
+// the one with conflicting tags.
+func TestFieldsOfReturnsConflictError(t *testing.T) {
+	_, err := FieldsOf(reflect.TypeOf(conflictingTagsItem{}))
+	if err == nil {
+		t.Fatal("expected an error for conflicting dynamodbav/json names, got nil")
+	}
+}
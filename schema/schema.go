@@ -0,0 +1,147 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// KeySchema names one attribute participating in a primary or secondary key.
+type KeySchema struct {
+	Name string
+}
+
+// SecondaryIndex describes one local or global secondary index.
+type SecondaryIndex struct {
+	Name string
+	Key  []KeySchema
+}
+
+// Schema is a struct type's resolved key structure.
+type Schema struct {
+	Key                    []KeySchema
+	LocalSecondaryIndexes  []SecondaryIndex
+	GlobalSecondaryIndexes []SecondaryIndex
+}
+
+var (
+	schemaMu    sync.RWMutex
+	schemaCache = map[resolverKey]*Schema{}
+)
+
+// GetSchema returns item's resolved Schema, built from its `dynami`
+// struct tags using the default TagResolver and cached per type. It
+// returns an error if the type's fields can't be resolved, e.g. a
+// field's dynamodbav and json tags disagree.
+//
+// A field is tagged `dynami:"hash"` or `dynami:"range"` to mark it part
+// of the table's primary key, and `dynami:"index:Name,hash"` or
+// `dynami:"lindex:Name,range"` to add it to a global or local secondary
+// index named Name. Multiple tags on one field are separated by ";".
+func GetSchema(item interface{}) (*Schema, error) {
+	return GetSchemaWithResolver(item, DefaultResolver())
+}
+
+// GetSchemaWithResolver is GetSchema, but resolves fields with r instead
+// of the package's default TagResolver. Clients that want per-Client tag
+// resolution call this instead of GetSchema.
+func GetSchemaWithResolver(item interface{}, r TagResolver) (*Schema, error) {
+	t := reflect.TypeOf(item)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	key := resolverKey{resolver: r, typ: t}
+
+	schemaMu.RLock()
+	s, ok := schemaCache[key]
+	schemaMu.RUnlock()
+	if ok {
+		return s, nil
+	}
+
+	fields, err := ResolveFields(r, t)
+	if err != nil {
+		return nil, err
+	}
+	s = buildSchema(t, fields)
+
+	schemaMu.Lock()
+	schemaCache[key] = s
+	schemaMu.Unlock()
+
+	return s, nil
+}
+
+func buildSchema(t reflect.Type, fields []Field) *Schema {
+	s := &Schema{}
+	global := &indexSet{byName: map[string]*SecondaryIndex{}}
+	local := &indexSet{byName: map[string]*SecondaryIndex{}}
+
+	for _, f := range fields {
+		tag := t.FieldByIndex(f.Index).Tag.Get("dynami")
+		if tag == "" {
+			continue
+		}
+
+		for _, part := range strings.Split(tag, ";") {
+			parseKeyTag(s, global, local, f.Name, part)
+		}
+	}
+
+	s.GlobalSecondaryIndexes = global.ordered()
+	s.LocalSecondaryIndexes = local.ordered()
+
+	return s
+}
+
+// indexSet collects SecondaryIndexes keyed by name while preserving the
+// order they were first seen in, so callers don't end up depending on Go's
+// randomized map iteration order.
+type indexSet struct {
+	byName map[string]*SecondaryIndex
+	names  []string
+}
+
+func (idx *indexSet) get(name string) *SecondaryIndex {
+	si, ok := idx.byName[name]
+	if !ok {
+		si = &SecondaryIndex{Name: name}
+		idx.byName[name] = si
+		idx.names = append(idx.names, name)
+	}
+	return si
+}
+
+func (idx *indexSet) ordered() []SecondaryIndex {
+	if len(idx.names) == 0 {
+		return nil
+	}
+
+	out := make([]SecondaryIndex, len(idx.names))
+	for i, name := range idx.names {
+		out[i] = *idx.byName[name]
+	}
+	return out
+}
+
+func parseKeyTag(s *Schema, global, local *indexSet, name, part string) {
+	part = strings.TrimSpace(part)
+
+	switch {
+	case part == "hash" || part == "range":
+		s.Key = append(s.Key, KeySchema{Name: name})
+
+	case strings.HasPrefix(part, "index:"):
+		addToIndex(global, part[len("index:"):], name)
+
+	case strings.HasPrefix(part, "lindex:"):
+		addToIndex(local, part[len("lindex:"):], name)
+	}
+}
+
+func addToIndex(indexes *indexSet, spec, name string) {
+	indexName := strings.TrimSpace(strings.SplitN(spec, ",", 2)[0])
+	idx := indexes.get(indexName)
+	idx.Key = append(idx.Key, KeySchema{Name: name})
+}
@@ -0,0 +1,48 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+type multiIndexItem struct {
+	ID     string `dynamodbav:"id" dynami:"hash"`
+	First  string `dynamodbav:"first" dynami:"index:idx1,hash"`
+	Second string `dynamodbav:"second" dynami:"index:idx2,hash"`
+	Third  string `dynamodbav:"third" dynami:"index:idx3,hash"`
+}
+
+// TestBuildSchemaIndexOrderIsDeterministic guards against buildSchema
+// depending on Go's randomized map iteration order: GlobalSecondaryIndexes
+// must come back in the same, first-seen order every time, since
+// getSecondaryKey picks the first nonempty one.
+func TestBuildSchemaIndexOrderIsDeterministic(t *testing.T) {
+	typ := reflect.TypeOf(multiIndexItem{})
+	fields, err := ResolveFields(tagResolver{}, typ)
+	if err != nil {
+		t.Fatalf("ResolveFields: %v", err)
+	}
+
+	var want []string
+	for i := 0; i < 20; i++ {
+		s := buildSchema(typ, fields)
+
+		got := make([]string, len(s.GlobalSecondaryIndexes))
+		for j, idx := range s.GlobalSecondaryIndexes {
+			got[j] = idx.Name
+		}
+
+		if want == nil {
+			want = got
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("index order changed between calls: want %v, got %v", want, got)
+		}
+	}
+
+	expected := []string{"idx1", "idx2", "idx3"}
+	if !reflect.DeepEqual(want, expected) {
+		t.Fatalf("expected index order %v, got %v", expected, want)
+	}
+}
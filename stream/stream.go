@@ -0,0 +1,490 @@
+// Package stream consumes a DynamoDB table's stream and delivers
+// item-level changes as a channel of Events, decoded into the caller's
+// own struct type using the same dynamodbav/json tag rules dynami uses
+// for items.
+package stream
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	streamtypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+)
+
+// IteratorType selects where in a shard a new shard iterator begins reading.
+type IteratorType string
+
+// Iterator types supported by GetShardIterator.
+const (
+	TrimHorizon IteratorType = "TRIM_HORIZON"
+	Latest      IteratorType = "LATEST"
+
+	// AfterSequenceNumber is used internally by shardIterator to resume
+	// a shard from its last checkpointed sequence number. It is not a
+	// valid argument to WithIteratorType: a freshly-discovered shard has
+	// no sequence number to resume from, and GetShardIterator rejects
+	// AFTER_SEQUENCE_NUMBER without one. Subscribe rejects it there; the
+	// checkpoint-resume path is the only way to get this behavior.
+	AfterSequenceNumber IteratorType = "AFTER_SEQUENCE_NUMBER"
+)
+
+// EventType is the kind of modification that produced an Event.
+type EventType string
+
+// Event types as reported by a stream record's eventName.
+const (
+	InsertEvent EventType = "INSERT"
+	ModifyEvent EventType = "MODIFY"
+	RemoveEvent EventType = "REMOVE"
+)
+
+// Event is a single stream record decoded into the type passed to
+// Subscribe. NewImage and OldImage share that type; either may be nil
+// depending on the table's StreamViewType and Type.
+type Event struct {
+	Type           EventType
+	ShardID        string
+	SequenceNumber string
+
+	NewImage interface{}
+	OldImage interface{}
+
+	// Err is set instead of the fields above when the consumer could
+	// not read or decode a record. After a decode error the shard keeps
+	// being read. After an error getting a shard's iterator or records,
+	// that shard is not read again and its child shards are started;
+	// the other shards and shard discovery keep running. Err is reported
+	// so the caller can decide whether to give up.
+	Err error
+}
+
+// Checkpointer records the last sequence number processed for a shard
+// so consumption can resume after a restart instead of replaying the
+// whole TRIM_HORIZON.
+type Checkpointer interface {
+	// Get returns the last checkpointed sequence number for shardID,
+	// or "" if shardID has no checkpoint yet.
+	Get(ctx context.Context, tableName, shardID string) (string, error)
+
+	// Set records seqNum as the last processed sequence number for shardID.
+	Set(ctx context.Context, tableName, shardID, seqNum string) error
+}
+
+// NewMemoryCheckpointer returns a Checkpointer backed by an in-process
+// map. Checkpoints do not survive a restart; use NewDynamoDBCheckpointer
+// for that.
+func NewMemoryCheckpointer() Checkpointer {
+	return &memoryCheckpointer{seqNums: map[string]string{}}
+}
+
+type memoryCheckpointer struct {
+	mu      sync.Mutex
+	seqNums map[string]string
+}
+
+func (c *memoryCheckpointer) Get(_ context.Context, tableName, shardID string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seqNums[tableName+"/"+shardID], nil
+}
+
+func (c *memoryCheckpointer) Set(_ context.Context, tableName, shardID, seqNum string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seqNums[tableName+"/"+shardID] = seqNum
+	return nil
+}
+
+// NewDynamoDBCheckpointer returns a Checkpointer that stores each shard's
+// last processed sequence number as an item in checkpointTable. The
+// table must have a string partition key named "ShardID".
+func NewDynamoDBCheckpointer(cfg aws.Config, checkpointTable string) Checkpointer {
+	return &dynamoCheckpointer{
+		db:    dynamodb.NewFromConfig(cfg),
+		table: checkpointTable,
+	}
+}
+
+type dynamoCheckpointer struct {
+	db    *dynamodb.Client
+	table string
+}
+
+func (c *dynamoCheckpointer) Get(ctx context.Context, tableName, shardID string) (string, error) {
+	out, err := c.db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(c.table),
+		Key: map[string]dbtypes.AttributeValue{
+			"ShardID": &dbtypes.AttributeValueMemberS{Value: tableName + "/" + shardID},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return "", fmt.Errorf("stream: get checkpoint (%v)", err)
+	}
+	if out.Item == nil {
+		return "", nil
+	}
+
+	v, ok := out.Item["SequenceNumber"].(*dbtypes.AttributeValueMemberS)
+	if !ok {
+		return "", nil
+	}
+	return v.Value, nil
+}
+
+func (c *dynamoCheckpointer) Set(ctx context.Context, tableName, shardID, seqNum string) error {
+	_, err := c.db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(c.table),
+		Item: map[string]dbtypes.AttributeValue{
+			"ShardID":        &dbtypes.AttributeValueMemberS{Value: tableName + "/" + shardID},
+			"SequenceNumber": &dbtypes.AttributeValueMemberS{Value: seqNum},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("stream: set checkpoint (%v)", err)
+	}
+	return nil
+}
+
+// Options configure Subscribe.
+type Options struct {
+	iteratorType IteratorType
+	checkpointer Checkpointer
+	pollInterval time.Duration
+}
+
+// Option sets a Subscribe option.
+type Option func(*Options)
+
+// WithIteratorType sets where an unconsumed shard starts reading from:
+// TrimHorizon or Latest. Defaults to TrimHorizon. AfterSequenceNumber is
+// not accepted here; Subscribe uses it automatically, via a shard's
+// checkpoint, once one exists.
+func WithIteratorType(t IteratorType) Option {
+	return func(o *Options) { o.iteratorType = t }
+}
+
+// WithCheckpointer sets the Checkpointer used to resume shards across
+// restarts. Defaults to an in-memory checkpointer.
+func WithCheckpointer(c Checkpointer) Option {
+	return func(o *Options) { o.checkpointer = c }
+}
+
+// WithPollInterval sets how often a shard with no new records is
+// re-polled. Defaults to one second.
+func WithPollInterval(d time.Duration) Option {
+	return func(o *Options) { o.pollInterval = d }
+}
+
+// Subscribe opens tableName's stream and returns a channel of decoded
+// Events. itemType is a pointer to the struct NewImage/OldImage should
+// be unmarshalled into, e.g. &MyStruct{}; Subscribe only reads its type.
+//
+// Subscribe discovers shards automatically and keeps discovering new
+// ones as the table reshards. Each shard is consumed in its own
+// goroutine, honoring the DynamoDB Streams rule that a child shard isn't
+// read until its parent has been fully consumed. The returned channel is
+// closed when ctx is canceled.
+func Subscribe(ctx context.Context, cfg aws.Config, tableName string, itemType interface{}, opts ...Option) (<-chan Event, error) {
+	elemType := reflect.TypeOf(itemType)
+	if elemType == nil || elemType.Kind() != reflect.Ptr || elemType.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("stream: itemType must be a pointer to a struct")
+	}
+	elemType = elemType.Elem()
+
+	o := &Options{
+		iteratorType: TrimHorizon,
+		checkpointer: NewMemoryCheckpointer(),
+		pollInterval: time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.iteratorType != TrimHorizon && o.iteratorType != Latest {
+		return nil, fmt.Errorf(
+			"stream: WithIteratorType must be TrimHorizon or Latest, got %v; "+
+				"AfterSequenceNumber is used automatically once a shard has a checkpoint",
+			o.iteratorType,
+		)
+	}
+
+	dbc := dynamodb.NewFromConfig(cfg)
+	out, err := dbc.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("stream: describe table (%v)", err)
+	}
+	if out.Table.LatestStreamArn == nil {
+		return nil, fmt.Errorf("stream: table (%v) has no stream enabled", tableName)
+	}
+
+	c := &consumer{
+		streams: dynamodbstreams.NewFromConfig(cfg),
+		opts:    *o,
+
+		streamARN: *out.Table.LatestStreamArn,
+		tableName: tableName,
+		elemType:  elemType,
+
+		events: make(chan Event),
+		done:   map[string]chan struct{}{},
+	}
+
+	go c.run(ctx)
+
+	return c.events, nil
+}
+
+type consumer struct {
+	streams *dynamodbstreams.Client
+	opts    Options
+
+	streamARN string
+	tableName string
+	elemType  reflect.Type
+
+	events chan Event
+
+	mu   sync.Mutex
+	done map[string]chan struct{} // shardID -> closed when shard fully consumed
+}
+
+// run discovers shards periodically and starts a goroutine per new
+// shard, waiting for each shard's parent to finish before starting it.
+func (c *consumer) run(ctx context.Context) {
+	defer close(c.events)
+
+	started := map[string]bool{}
+	for {
+		desc, err := c.streams.DescribeStream(ctx, &dynamodbstreams.DescribeStreamInput{
+			StreamArn: aws.String(c.streamARN),
+		})
+		if err != nil {
+			select {
+			case c.events <- Event{Err: fmt.Errorf("stream: describe stream (%v)", err)}:
+			case <-ctx.Done():
+				return
+			}
+		} else {
+			for _, shard := range desc.StreamDescription.Shards {
+				id := *shard.ShardId
+				if started[id] {
+					continue
+				}
+				started[id] = true
+
+				// Root shards (no ParentShardId) have nothing to wait
+				// on and can start immediately. Handing consumeShard
+				// its own done channel here would deadlock: that
+				// channel is only closed by consumeShard's own return,
+				// which never happens until it stops waiting on it.
+				wait := closedChan
+				if shard.ParentShardId != nil {
+					wait = c.shardDone(*shard.ParentShardId)
+				}
+
+				go c.consumeShard(ctx, id, wait)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.opts.pollInterval):
+		}
+	}
+}
+
+// closedChan is already closed, so reading from it never blocks. It's
+// handed to consumeShard as parentDone for shards with no parent.
+var closedChan = func() <-chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// emit sends ev to the events channel, returning false if ctx was
+// canceled first so the caller can stop consuming its shard.
+func (c *consumer) emit(ctx context.Context, ev Event) bool {
+	select {
+	case c.events <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// shardDone returns the channel that's closed once shardID finishes, creating it if needed.
+func (c *consumer) shardDone(shardID string) chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch, ok := c.done[shardID]
+	if !ok {
+		ch = make(chan struct{})
+		c.done[shardID] = ch
+	}
+	return ch
+}
+
+func (c *consumer) consumeShard(ctx context.Context, shardID string, parentDone <-chan struct{}) {
+	defer close(c.shardDone(shardID))
+
+	select {
+	case <-parentDone:
+	case <-ctx.Done():
+		return
+	}
+
+	iter, err := c.shardIterator(ctx, shardID)
+	if err != nil {
+		c.emit(ctx, Event{ShardID: shardID, Err: err})
+		return
+	}
+
+	for iter != nil {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		out, err := c.streams.GetRecords(ctx, &dynamodbstreams.GetRecordsInput{
+			ShardIterator: iter,
+		})
+		if err != nil {
+			c.emit(ctx, Event{ShardID: shardID, Err: fmt.Errorf("stream: get records (%v)", err)})
+			return
+		}
+
+		for _, rec := range out.Records {
+			ev, err := c.decode(shardID, rec)
+			if err != nil {
+				ev = Event{ShardID: shardID, Err: err}
+			}
+			if !c.emit(ctx, ev) {
+				return
+			}
+
+			if ev.Err == nil {
+				if err := c.opts.checkpointer.Set(ctx, c.tableName, shardID, ev.SequenceNumber); err != nil {
+					c.emit(ctx, Event{ShardID: shardID, Err: fmt.Errorf("stream: checkpoint (%v)", err)})
+				}
+			}
+		}
+
+		iter = out.NextShardIterator
+		if iter != nil && len(out.Records) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.opts.pollInterval):
+			}
+		}
+	}
+}
+
+// shardIterator resumes from a checkpoint if one exists, otherwise
+// starts from the configured IteratorType.
+func (c *consumer) shardIterator(ctx context.Context, shardID string) (*string, error) {
+	input := &dynamodbstreams.GetShardIteratorInput{
+		StreamArn: aws.String(c.streamARN),
+		ShardId:   aws.String(shardID),
+	}
+
+	seqNum, err := c.opts.checkpointer.Get(ctx, c.tableName, shardID)
+	if err != nil {
+		return nil, fmt.Errorf("stream: read checkpoint (%v)", err)
+	}
+
+	if seqNum != "" {
+		input.ShardIteratorType = streamtypes.ShardIteratorType(AfterSequenceNumber)
+		input.SequenceNumber = aws.String(seqNum)
+	} else {
+		input.ShardIteratorType = streamtypes.ShardIteratorType(c.opts.iteratorType)
+	}
+
+	out, err := c.streams.GetShardIterator(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("stream: get shard iterator (%v)", err)
+	}
+	return out.ShardIterator, nil
+}
+
+func (c *consumer) decode(shardID string, rec streamtypes.Record) (Event, error) {
+	ev := Event{
+		Type:           EventType(rec.EventName),
+		ShardID:        shardID,
+		SequenceNumber: aws.ToString(rec.Dynamodb.SequenceNumber),
+	}
+
+	if img := rec.Dynamodb.NewImage; img != nil {
+		v := reflect.New(c.elemType)
+		if err := attributevalue.UnmarshalMap(toAttributeValueMap(img), v.Interface()); err != nil {
+			return Event{}, fmt.Errorf("stream: unmarshal new image (%v)", err)
+		}
+		ev.NewImage = v.Interface()
+	}
+
+	if img := rec.Dynamodb.OldImage; img != nil {
+		v := reflect.New(c.elemType)
+		if err := attributevalue.UnmarshalMap(toAttributeValueMap(img), v.Interface()); err != nil {
+			return Event{}, fmt.Errorf("stream: unmarshal old image (%v)", err)
+		}
+		ev.OldImage = v.Interface()
+	}
+
+	return ev, nil
+}
+
+// toAttributeValueMap converts a dynamodbstreams image into the
+// dynamodb package's AttributeValue type so it can be passed to
+// attributevalue.UnmarshalMap. The two packages define wire-identical
+// but distinct Go types, so each value has to be walked and rebuilt.
+func toAttributeValueMap(img map[string]streamtypes.AttributeValue) map[string]dbtypes.AttributeValue {
+	out := make(map[string]dbtypes.AttributeValue, len(img))
+	for k, v := range img {
+		out[k] = convertAttributeValue(v)
+	}
+	return out
+}
+
+func convertAttributeValue(v streamtypes.AttributeValue) dbtypes.AttributeValue {
+	switch vv := v.(type) {
+	case *streamtypes.AttributeValueMemberS:
+		return &dbtypes.AttributeValueMemberS{Value: vv.Value}
+	case *streamtypes.AttributeValueMemberN:
+		return &dbtypes.AttributeValueMemberN{Value: vv.Value}
+	case *streamtypes.AttributeValueMemberBOOL:
+		return &dbtypes.AttributeValueMemberBOOL{Value: vv.Value}
+	case *streamtypes.AttributeValueMemberNULL:
+		return &dbtypes.AttributeValueMemberNULL{Value: vv.Value}
+	case *streamtypes.AttributeValueMemberB:
+		return &dbtypes.AttributeValueMemberB{Value: vv.Value}
+	case *streamtypes.AttributeValueMemberSS:
+		return &dbtypes.AttributeValueMemberSS{Value: vv.Value}
+	case *streamtypes.AttributeValueMemberNS:
+		return &dbtypes.AttributeValueMemberNS{Value: vv.Value}
+	case *streamtypes.AttributeValueMemberBS:
+		return &dbtypes.AttributeValueMemberBS{Value: vv.Value}
+	case *streamtypes.AttributeValueMemberM:
+		return &dbtypes.AttributeValueMemberM{Value: toAttributeValueMap(vv.Value)}
+	case *streamtypes.AttributeValueMemberL:
+		list := make([]dbtypes.AttributeValue, len(vv.Value))
+		for i, e := range vv.Value {
+			list[i] = convertAttributeValue(e)
+		}
+		return &dbtypes.AttributeValueMemberL{Value: list}
+	default:
+		return &dbtypes.AttributeValueMemberNULL{Value: true}
+	}
+}
@@ -0,0 +1,152 @@
+package stream
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	streamtypes "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+)
+
+func TestClosedChanIsClosed(t *testing.T) {
+	select {
+	case _, ok := <-closedChan:
+		if ok {
+			t.Fatal("closedChan: expected channel to be closed, got a value")
+		}
+	default:
+		t.Fatal("closedChan: expected a read to not block")
+	}
+}
+
+func TestShardDoneIsIdempotent(t *testing.T) {
+	c := &consumer{done: map[string]chan struct{}{}}
+
+	a := c.shardDone("shard-1")
+	b := c.shardDone("shard-1")
+	if a != b {
+		t.Fatal("shardDone: expected the same channel on repeated calls for the same shard")
+	}
+
+	other := c.shardDone("shard-2")
+	if a == other {
+		t.Fatal("shardDone: expected different channels for different shards")
+	}
+}
+
+// TestRootShardDoesNotDeadlock guards the bug where a root shard (no
+// ParentShardId) was handed its own done channel as parentDone:
+// consumeShard's first step waits on that channel, but only
+// consumeShard's own return closes it, so it would wait forever. Root
+// shards must instead start from the already-closed closedChan. The test
+// checks only that a read from closedChan completes at once; it does not
+// run run() or consumeShard.
+func TestRootShardDoesNotDeadlock(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		<-closedChan // what run() now hands consumeShard for root shards
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected closedChan read to complete immediately, it blocked")
+	}
+}
+
+// TestSubscribeRejectsAfterSequenceNumber guards against
+// WithIteratorType(AfterSequenceNumber) reaching GetShardIterator with
+// no SequenceNumber: a freshly-discovered shard has no checkpoint yet,
+// so DynamoDB Streams would reject the call. Subscribe must reject the
+// option itself instead, before any AWS call is made.
+func TestSubscribeRejectsAfterSequenceNumber(t *testing.T) {
+	_, err := Subscribe(
+		context.Background(),
+		aws.Config{},
+		"table",
+		&struct{ ID string }{},
+		WithIteratorType(AfterSequenceNumber),
+	)
+	if err == nil {
+		t.Fatal("expected an error for WithIteratorType(AfterSequenceNumber), got nil")
+	}
+}
+
+type streamItemMeta struct {
+	Count int `dynamodbav:"count"`
+}
+
+type streamItem struct {
+	ID   string         `dynamodbav:"id"`
+	Name string         `dynamodbav:"name"`
+	Tags []string       `dynamodbav:"tags"`
+	Meta streamItemMeta `dynamodbav:"meta"`
+}
+
+// TestConsumerDecodeUnmarshalsImages guards decode/convertAttributeValue,
+// the actual record-unmarshalling feature this subsystem exists for: a
+// dynamodbstreams Record's NewImage/OldImage must come out as the
+// caller's struct type, including nested M (map) and L (list)
+// attributes converted across the dynamodbstreams/dynamodb type split.
+func TestConsumerDecodeUnmarshalsImages(t *testing.T) {
+	c := &consumer{elemType: reflect.TypeOf(streamItem{})}
+
+	rec := streamtypes.Record{
+		EventName: streamtypes.OperationTypeModify,
+		Dynamodb: &streamtypes.StreamRecord{
+			SequenceNumber: aws.String("123"),
+			NewImage: map[string]streamtypes.AttributeValue{
+				"id":   &streamtypes.AttributeValueMemberS{Value: "1"},
+				"name": &streamtypes.AttributeValueMemberS{Value: "new"},
+				"tags": &streamtypes.AttributeValueMemberL{Value: []streamtypes.AttributeValue{
+					&streamtypes.AttributeValueMemberS{Value: "a"},
+					&streamtypes.AttributeValueMemberS{Value: "b"},
+				}},
+				"meta": &streamtypes.AttributeValueMemberM{Value: map[string]streamtypes.AttributeValue{
+					"count": &streamtypes.AttributeValueMemberN{Value: "3"},
+				}},
+			},
+			OldImage: map[string]streamtypes.AttributeValue{
+				"id":   &streamtypes.AttributeValueMemberS{Value: "1"},
+				"name": &streamtypes.AttributeValueMemberS{Value: "old"},
+			},
+		},
+	}
+
+	ev, err := c.decode("shard-1", rec)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if ev.Type != ModifyEvent {
+		t.Fatalf("expected ModifyEvent, got %v", ev.Type)
+	}
+	if ev.SequenceNumber != "123" {
+		t.Fatalf("expected sequence number 123, got %v", ev.SequenceNumber)
+	}
+
+	newImg, ok := ev.NewImage.(*streamItem)
+	if !ok {
+		t.Fatalf("expected NewImage to be *streamItem, got %T", ev.NewImage)
+	}
+	if newImg.ID != "1" || newImg.Name != "new" {
+		t.Fatalf("unexpected NewImage: %+v", newImg)
+	}
+	if len(newImg.Tags) != 2 || newImg.Tags[0] != "a" || newImg.Tags[1] != "b" {
+		t.Fatalf("expected tags [a b], got %v", newImg.Tags)
+	}
+	if newImg.Meta.Count != 3 {
+		t.Fatalf("expected nested meta.count 3, got %v", newImg.Meta.Count)
+	}
+
+	oldImg, ok := ev.OldImage.(*streamItem)
+	if !ok {
+		t.Fatalf("expected OldImage to be *streamItem, got %T", ev.OldImage)
+	}
+	if oldImg.ID != "1" || oldImg.Name != "old" {
+		t.Fatalf("unexpected OldImage: %+v", oldImg)
+	}
+}
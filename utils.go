@@ -1,15 +1,18 @@
 package dynami
 
 import (
+	"crypto/rand"
 	"fmt"
-	"math/rand"
+	"math/big"
+	mrand "math/rand"
 	"reflect"
-	"strings"
+	"strconv"
 
+	exp "github.com/robskie/dynami/expression"
 	sc "github.com/robskie/dynami/schema"
 
-	db "github.com/aws/aws-sdk-go/service/dynamodb"
-	dbattribute "github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	dbattribute "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	dbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 type indexType string
@@ -30,23 +33,24 @@ type (
 		indexType indexType
 	}
 
-	dbitem map[string]*db.AttributeValue
+	dbitem map[string]dbtypes.AttributeValue
 )
 
 // getKey returns a key from a value. This is done by
 // first checking the primary key in val and returns it
 // if it's nonempty. If it's empty, it will return the
-// first nonempty secondary key.
-func getKey(item interface{}) (*dbkey, error) {
-	k, err := getPrimaryKey(item)
+// first nonempty secondary key. r resolves item's tags,
+// same as getPrimaryKey/getSecondaryKey.
+func getKey(r sc.TagResolver, item interface{}) (*dbkey, error) {
+	k, err := getPrimaryKey(r, item)
 	if err != nil {
-		k, err = getSecondaryKey(item)
+		k, err = getSecondaryKey(r, item)
 	}
 
 	return k, err
 }
 
-func getPrimaryKey(item interface{}) (*dbkey, error) {
+func getPrimaryKey(r sc.TagResolver, item interface{}) (*dbkey, error) {
 	val := reflect.Indirect(reflect.ValueOf(item))
 	item = val.Interface()
 
@@ -55,10 +59,14 @@ func getPrimaryKey(item interface{}) (*dbkey, error) {
 		return nil, fmt.Errorf("dynami: invalid item (%v)", err)
 	}
 
-	schema := sc.GetSchema(item)
+	schema, err := sc.GetSchemaWithResolver(item, r)
+	if err != nil {
+		return nil, fmt.Errorf("dynami: resolve schema (%v)", err)
+	}
+
 	key := &dbkey{value: dbitem{}}
 	for _, k := range schema.Key {
-		v, err := valueByName(val, k.Name)
+		v, err := valueByName(r, val, k.Name)
 		if err != nil {
 			return nil, fmt.Errorf("dynami: key (%v) has no value", k.Name)
 		}
@@ -76,7 +84,7 @@ func getPrimaryKey(item interface{}) (*dbkey, error) {
 	return key, nil
 }
 
-func getSecondaryKey(item interface{}) (*dbkey, error) {
+func getSecondaryKey(r sc.TagResolver, item interface{}) (*dbkey, error) {
 	val := reflect.Indirect(reflect.ValueOf(item))
 	item = val.Interface()
 
@@ -85,8 +93,12 @@ func getSecondaryKey(item interface{}) (*dbkey, error) {
 		return nil, fmt.Errorf("dynami: invalid item (%v)", err)
 	}
 
+	schema, err := sc.GetSchemaWithResolver(item, r)
+	if err != nil {
+		return nil, fmt.Errorf("dynami: resolve schema (%v)", err)
+	}
+
 	key := &dbkey{value: dbitem{}}
-	schema := sc.GetSchema(item)
 
 	globalIdxMarker := len(schema.LocalSecondaryIndexes)
 	secondaryIdxs := make([]sc.SecondaryIndex, len(schema.LocalSecondaryIndexes))
@@ -97,8 +109,8 @@ Indices:
 	// Get secondary indices
 	for i, idx := range secondaryIdxs {
 		for _, k := range idx.Key {
-			v := val.FieldByName(k.Name)
-			if isZeroValue(v) {
+			v, err := valueByName(r, val, k.Name)
+			if err != nil || isZeroValue(v) {
 				key.value = dbitem{}
 				continue Indices
 			}
@@ -122,20 +134,116 @@ Indices:
 	return key, nil
 }
 
-func removeEmptyAttr(item dbitem) dbitem {
+// condition builds a KeyConditionExpression-ready expression.KeyConditionBuilder
+// from k. Key attribute names are always treated as a single, literal
+// attribute name via expression.NameNoDotSplit, even if they contain
+// dots, since a table's key schema names are never document paths.
+func (k *dbkey) condition() (exp.KeyConditionBuilder, error) {
+	var cond exp.KeyConditionBuilder
+
+	first := true
+	for name, value := range k.value {
+		c := exp.Key(exp.NameNoDotSplit(name)).Equal(exp.RawValue(value))
+		if first {
+			cond = c
+			first = false
+			continue
+		}
+		cond = exp.KeyAnd(cond, c)
+	}
+
+	if first {
+		return exp.KeyConditionBuilder{}, fmt.Errorf("dynami: empty key")
+	}
+	return cond, nil
+}
+
+// MarshalOptions controls how removeEmptyAttr treats attributes that
+// would otherwise be silently dropped. The zero value reproduces this
+// package's original behavior: strip empty strings and explicit NULLs,
+// recursing into M (map) attributes only.
+type MarshalOptions struct {
+	// AllowEmptyString keeps empty string attributes instead of
+	// stripping them, matching DynamoDB's native support for them.
+	AllowEmptyString bool
+
+	// PreserveNull keeps explicit NULL attributes instead of stripping them.
+	PreserveNull bool
+
+	// OmitZeroNumbers strips number attributes whose value is zero.
+	OmitZeroNumbers bool
+
+	// RecurseLists applies these rules inside L (list) attributes too,
+	// not just M (map) attributes.
+	RecurseLists bool
+}
+
+// removeEmptyAttr strips empty-looking attributes from item according
+// to opts, recursing into M attributes and, if opts.RecurseLists is
+// set, L attributes. fields overrides opts per attribute name via a
+// field's `dynamodbav:",omitempty"`/`",allowempty"` tag, resolved once
+// by the caller from the item's type with schema.ResolveFields and the
+// Client's TagResolver; it does not apply
+// inside nested M/L values, since their originating struct type isn't
+// tracked through marshalling.
+func removeEmptyAttr(item dbitem, opts MarshalOptions, fields map[string]sc.Field) dbitem {
 	for attrName, attrValue := range item {
-		if attrValue.S != nil && *attrValue.S == "" {
-			delete(item, attrName)
-		} else if attrValue.NULL != nil && *attrValue.NULL == true {
-			delete(item, attrName)
-		} else if attrValue.M != nil {
-			removeEmptyAttr(attrValue.M)
+		allowEmptyString := opts.AllowEmptyString
+		preserveNull := opts.PreserveNull
+		if f, ok := fields[attrName]; ok {
+			if f.AllowEmpty {
+				allowEmptyString, preserveNull = true, true
+			}
+			if f.OmitEmpty {
+				allowEmptyString, preserveNull = false, false
+			}
+		}
+
+		switch v := attrValue.(type) {
+		case *dbtypes.AttributeValueMemberS:
+			if v.Value == "" && !allowEmptyString {
+				delete(item, attrName)
+			}
+		case *dbtypes.AttributeValueMemberNULL:
+			if v.Value && !preserveNull {
+				delete(item, attrName)
+			}
+		case *dbtypes.AttributeValueMemberN:
+			if opts.OmitZeroNumbers && isZeroNumber(v.Value) {
+				delete(item, attrName)
+			}
+		case *dbtypes.AttributeValueMemberM:
+			removeEmptyAttr(v.Value, opts, nil)
+		case *dbtypes.AttributeValueMemberL:
+			if opts.RecurseLists {
+				removeEmptyListAttr(v.Value, opts)
+			}
 		}
 	}
 
 	return item
 }
 
+// removeEmptyListAttr applies opts inside a list's M/L elements. List
+// elements that are themselves empty strings or NULLs are left alone;
+// dropping a list element would change the list's length and meaning
+// in a way dropping a map key doesn't.
+func removeEmptyListAttr(list []dbtypes.AttributeValue, opts MarshalOptions) {
+	for _, v := range list {
+		switch vv := v.(type) {
+		case *dbtypes.AttributeValueMemberM:
+			removeEmptyAttr(vv.Value, opts, nil)
+		case *dbtypes.AttributeValueMemberL:
+			removeEmptyListAttr(vv.Value, opts)
+		}
+	}
+}
+
+func isZeroNumber(n string) bool {
+	f, err := strconv.ParseFloat(n, 64)
+	return err == nil && f == 0
+}
+
 func toPtr(v interface{}) interface{} {
 	switch vv := v.(type) {
 	case string:
@@ -167,12 +275,16 @@ func isZeroValue(val reflect.Value) bool {
 	return !val.IsValid() || val.Interface() == reflect.Zero(val.Type()).Interface()
 }
 
-func valueByName(val reflect.Value, name string) (reflect.Value, error) {
+func valueByName(r sc.TagResolver, val reflect.Value, name string) (reflect.Value, error) {
 	v := reflect.Value{}
 	if val.Kind() == reflect.Struct {
 		v = val.FieldByName(name)
 		if !v.IsValid() {
-			v = fieldByNameTag(val, name)
+			var err error
+			v, err = fieldByNameTag(r, val, name)
+			if err != nil {
+				return reflect.Value{}, err
+			}
 		}
 	} else if val.Kind() == reflect.Map {
 		v = val.MapIndex(reflect.ValueOf(name))
@@ -184,30 +296,23 @@ func valueByName(val reflect.Value, name string) (reflect.Value, error) {
 	return v, nil
 }
 
-func fieldByNameTag(val reflect.Value, name string) reflect.Value {
-	t := val.Type()
-	nf := t.NumField()
-	for i := 0; i < nf; i++ {
-		f := t.Field(i)
-
-		// Consider only exported fields
-		if f.PkgPath != "" {
-			continue
-		}
-
-		// Get name from dynamodbav or json tag
-		nameTag := f.Tag.Get("dynamodbav")
-		if nameTag == "" {
-			nameTag = f.Tag.Get("json")
-		}
+// fieldByNameTag finds the field of val tagged with the DynamoDB
+// attribute name, walking embedded structs the way encoding/json does.
+// Resolution is delegated to schema.ResolveFields, which also handles
+// the dynamodbav/json tag precedence and caches the reflection work.
+func fieldByNameTag(r sc.TagResolver, val reflect.Value, name string) (reflect.Value, error) {
+	fields, err := sc.ResolveFields(r, val.Type())
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("dynami: resolve schema (%v)", err)
+	}
 
-		tags := strings.Split(nameTag, ",")
-		if len(tags) > 0 && tags[0] == name {
-			return val.FieldByName(f.Name)
+	for _, f := range fields {
+		if f.Name == name {
+			return val.FieldByIndex(f.Index), nil
 		}
 	}
 
-	return reflect.Value{}
+	return reflect.Value{}, nil
 }
 
 func checkType(item interface{}, types ...interface{}) error {
@@ -298,12 +403,45 @@ func min(a, b int) int {
 	return b
 }
 
-func randString(n int) string {
-	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// NewClientRequestToken returns a random, base62 string of length n
+// suitable for use as a TransactWriteItems ClientRequestToken, so the
+// SDK's idempotent-retry behavior has a stable token to key off of
+// across retries of the same logical request.
+func NewClientRequestToken(n int) string {
+	return randString(n, false)
+}
+
+// randString returns a random, base62 string of length n, using
+// crypto/rand unless insecure is set, in which case math/rand's global
+// source is used. insecure exists only for a Client constructed with
+// WithInsecureRandom in tests; production code should never set it,
+// since math/rand tokens are neither unpredictable nor collision-resistant
+// under concurrent use.
+func randString(n int, insecure bool) string {
+	if insecure {
+		return legacyRandString(n)
+	}
+
+	b := make([]byte, n)
+	alphabetLen := big.NewInt(int64(len(base62Alphabet)))
+	for i := range b {
+		c, err := rand.Int(rand.Reader, alphabetLen)
+		if err != nil {
+			// The OS entropy source is broken; there is no safe
+			// fallback for something meant to be collision-resistant.
+			panic("dynami: crypto/rand unavailable (" + err.Error() + ")")
+		}
+		b[i] = base62Alphabet[c.Int64()]
+	}
+	return string(b)
+}
 
+func legacyRandString(n int) string {
 	b := make([]byte, n)
 	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+		b[i] = base62Alphabet[mrand.Intn(len(base62Alphabet))]
 	}
 	return string(b)
 }
@@ -0,0 +1,89 @@
+package dynami
+
+import (
+	"testing"
+
+	exp "github.com/robskie/dynami/expression"
+	sc "github.com/robskie/dynami/schema"
+
+	dbattribute "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+)
+
+type secondaryKeyItem struct {
+	ID    string `dynamodbav:"id" dynami:"hash"`
+	Owner string `dynamodbav:"owner_id" dynami:"index:byOwner,hash"`
+}
+
+// TestGetSecondaryKeyUsesTaggedName guards getSecondaryKey against
+// bypassing the resolver: it used to read secondary key fields with
+// val.FieldByName(k.Name), which only works when the Go field name
+// matches the resolved attribute name. A dynamodbav-renamed field like
+// Owner ("owner_id") resolved to an invalid reflect.Value and was
+// silently treated as zero, so the index was skipped.
+func TestGetSecondaryKeyUsesTaggedName(t *testing.T) {
+	item := secondaryKeyItem{Owner: "user-1"}
+
+	k, err := getSecondaryKey(sc.DefaultResolver(), &item)
+	if err != nil {
+		t.Fatalf("getSecondaryKey: %v", err)
+	}
+
+	if k.indexName != "byOwner" {
+		t.Fatalf("expected index byOwner, got %v", k.indexName)
+	}
+	if _, ok := k.value["owner_id"]; !ok {
+		t.Fatalf("expected key value for owner_id, got %+v", k.value)
+	}
+}
+
+type conflictingKeyItem struct {
+	ID   string `dynamodbav:"id" dynami:"hash"`
+	Name string `dynamodbav:"name" json:"other_name"`
+}
+
+// TestGetPrimaryKeySurfacesResolveError guards against getPrimaryKey
+// masking a schema resolution error behind the generic "no valid key":
+// a struct with one field carrying conflicting dynamodbav/json names
+// must fail with that conflict, not a misleading "missing key" error
+// for an unrelated hash key.
+func TestGetPrimaryKeySurfacesResolveError(t *testing.T) {
+	item := conflictingKeyItem{ID: "1"}
+
+	_, err := getPrimaryKey(sc.DefaultResolver(), &item)
+	if err == nil {
+		t.Fatal("expected an error for conflicting dynamodbav/json names, got nil")
+	}
+}
+
+// TestDbkeyConditionUsesNameNoDotSplit guards dbkey.condition, the
+// helper QueryKey uses to turn a resolved key into a KeyConditionBuilder:
+// key attribute names must be treated as a single literal name via
+// exp.NameNoDotSplit, not split on "." like a document path, even when
+// the name itself contains a dot.
+func TestDbkeyConditionUsesNameNoDotSplit(t *testing.T) {
+	av, err := dbattribute.Marshal("user-1")
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	k := &dbkey{value: dbitem{"owner.id": av}}
+
+	cond, err := k.condition()
+	if err != nil {
+		t.Fatalf("condition: %v", err)
+	}
+
+	expr, err := exp.NewBuilder().WithKeyCondition(cond).Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	found := false
+	for _, name := range expr.Names() {
+		if name == "owner.id" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected owner.id to be aliased as a single literal name, got %+v", expr.Names())
+	}
+}